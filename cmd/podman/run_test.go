@@ -1,6 +1,10 @@
 package main
 
 import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
 	"testing"
 
 	ociv1 "github.com/opencontainers/image-spec/specs-go/v1"
@@ -65,11 +69,99 @@ func createCLI() cli.App {
 	return a
 }
 
-func getRuntimeSpec(c *cli.Context) *spec.Spec {
-	runtime, _ := getRuntime(c)
-	createConfig, _ := parseCreateOpts(c, runtime, "alpine", generateAlpineImageData())
-	runtimeSpec, _ := createConfigToOCISpec(createConfig)
-	return runtimeSpec
+// getRuntimeSpec resolves a runtime via runtimeProvider (which tests
+// substitute with a fake) and threads any error encountered while turning
+// the parsed CLI flags into an OCI spec back to the caller, rather than
+// discarding it and returning a zero-valued spec.
+func getRuntimeSpec(c *cli.Context) (*spec.Spec, error) {
+	runtime, err := runtimeProvider(c)
+	if err != nil {
+		return nil, fmt.Errorf("getRuntime: %w", err)
+	}
+	createConfig, err := parseCreateOpts(c, runtime, "alpine", generateAlpineImageData())
+	if err != nil {
+		return nil, fmt.Errorf("parseCreateOpts: %w", err)
+	}
+	runtimeSpec, err := createConfigToOCISpec(createConfig)
+	if err != nil {
+		return nil, fmt.Errorf("createConfigToOCISpec: %w", err)
+	}
+	return runtimeSpec, nil
+}
+
+// TestMain substitutes a minimal runtime stub for the duration of the
+// package's tests. parseCreateOpts only consults the runtime for
+// namespace/cgroup defaults, so a zero-value Runtime is enough to exercise
+// flag parsing without requiring root privileges or a configured storage
+// backend.
+func TestMain(m *testing.M) {
+	runtimeProvider = func(c *cli.Context) (*libpod.Runtime, error) {
+		return &libpod.Runtime{}, nil
+	}
+	os.Exit(m.Run())
+}
+
+// runCreate runs the create CLI with args appended to the base "podman
+// create alpine" invocation and returns the resulting OCI spec. It fails the
+// test immediately if the CLI or spec generation errors; callers that need
+// to assert on parse/spec-generation errors should use runCreateErr instead.
+func runCreate(t *testing.T, args ...string) *spec.Spec {
+	t.Helper()
+	a := createCLI()
+	if err := a.Run(append(cmd, args...)); err != nil {
+		t.Fatalf("createCLI().Run(%v) failed: %v", args, err)
+	}
+	s, err := getRuntimeSpec(CLI)
+	if err != nil {
+		t.Fatalf("getRuntimeSpec(%v) failed: %v", args, err)
+	}
+	return s
+}
+
+// runCreateErr mirrors runCreate but surfaces the first error encountered
+// while turning the parsed flags into an OCI spec, so invalid flag values
+// can be asserted as errors instead of silently producing a zero-valued
+// spec.
+func runCreateErr(t *testing.T, args ...string) error {
+	t.Helper()
+	a := createCLI()
+	if err := a.Run(append(cmd, args...)); err != nil {
+		return err
+	}
+	_, err := getRuntimeSpec(CLI)
+	return err
+}
+
+// assertResource walks a dotted field path (e.g.
+// "Linux.Resources.Pids.Limit") starting at the root of s and asserts that
+// the value found there equals want. Pointers anywhere along the path,
+// including the leaf, are transparently dereferenced; a nil pointer fails
+// the assertion with a helpful message instead of panicking.
+func assertResource(t *testing.T, s *spec.Spec, path string, want interface{}) {
+	t.Helper()
+	v := reflect.ValueOf(s)
+	for _, field := range strings.Split(path, ".") {
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				t.Fatalf("assertResource(%s): nil pointer before field %q", path, field)
+				return
+			}
+			v = v.Elem()
+		}
+		v = v.FieldByName(field)
+		if !v.IsValid() {
+			t.Fatalf("assertResource(%s): no such field %q", path, field)
+			return
+		}
+	}
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			t.Fatalf("assertResource(%s): value is nil", path)
+			return
+		}
+		v = v.Elem()
+	}
+	assert.Equal(t, want, v.Interface(), "unexpected value at %s", path)
 }
 
 // TestPIDsLimit verifies the inputed pid-limit is correctly defined in the spec
@@ -77,6 +169,171 @@ func TestPIDsLimit(t *testing.T) {
 	a := createCLI()
 	args := []string{"--pids-limit", "22"}
 	a.Run(append(cmd, args...))
-	runtimeSpec := getRuntimeSpec(CLI)
+	runtimeSpec, err := getRuntimeSpec(CLI)
+	assert.NoError(t, err)
 	assert.Equal(t, runtimeSpec.Linux.Resources.Pids.Limit, int64(22))
 }
+
+// TestCreateResourceFlags table-drives the scalar resource-limiting flags
+// handled by parseCreateOpts/createConfigToOCISpec, asserting each lands on
+// the expected field of the generated OCI spec.
+func TestCreateResourceFlags(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		path string
+		want interface{}
+	}{
+		{
+			name: "pids-limit",
+			args: []string{"--pids-limit", "22"},
+			path: "Linux.Resources.Pids.Limit",
+			want: int64(22),
+		},
+		{
+			name: "cpu-shares",
+			args: []string{"--cpu-shares", "512"},
+			path: "Linux.Resources.CPU.Shares",
+			want: uint64(512),
+		},
+		{
+			name: "cpuset-cpus",
+			args: []string{"--cpuset-cpus", "0-3"},
+			path: "Linux.Resources.CPU.Cpus",
+			want: "0-3",
+		},
+		{
+			name: "memory",
+			args: []string{"--memory", "100m"},
+			path: "Linux.Resources.Memory.Limit",
+			want: int64(104857600),
+		},
+		{
+			name: "memory-swap",
+			args: []string{"--memory", "100m", "--memory-swap", "200m"},
+			path: "Linux.Resources.Memory.Swap",
+			want: int64(209715200),
+		},
+		{
+			name: "blkio-weight",
+			args: []string{"--blkio-weight", "300"},
+			path: "Linux.Resources.BlockIO.Weight",
+			want: uint16(300),
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			s := runCreate(t, tt.args...)
+			assertResource(t, s, tt.path, tt.want)
+		})
+	}
+}
+
+// TestCreateCapabilities verifies that --cap-add/--cap-drop are reflected in
+// the process capability sets of the generated spec.
+func TestCreateCapabilities(t *testing.T) {
+	s := runCreate(t, "--cap-add", "SYS_ADMIN", "--cap-drop", "MKNOD")
+	assert.Contains(t, s.Process.Capabilities.Bounding, "CAP_SYS_ADMIN")
+	assert.NotContains(t, s.Process.Capabilities.Bounding, "CAP_MKNOD")
+}
+
+// TestCreateSysctl verifies --sysctl values are copied into the spec's
+// Linux.Sysctl map.
+func TestCreateSysctl(t *testing.T) {
+	s := runCreate(t, "--sysctl", "net.core.somaxconn=65535")
+	assert.Equal(t, "65535", s.Linux.Sysctl["net.core.somaxconn"])
+}
+
+// TestCreateReadOnly verifies --read-only marks the root filesystem
+// read-only in the spec.
+func TestCreateReadOnly(t *testing.T) {
+	s := runCreate(t, "--read-only")
+	assert.True(t, s.Root.Readonly)
+}
+
+// TestCreateUserAndHostname verifies --user and --hostname are threaded
+// through to the process and UTS configuration of the spec.
+func TestCreateUserAndHostname(t *testing.T) {
+	s := runCreate(t, "--user", "1000:1000", "--hostname", "testhost")
+	assert.Equal(t, uint32(1000), s.Process.User.UID)
+	assert.Equal(t, uint32(1000), s.Process.User.GID)
+	assert.Equal(t, "testhost", s.Hostname)
+}
+
+// TestCreateTmpfs verifies --tmpfs adds a tmpfs mount at the requested
+// destination.
+func TestCreateTmpfs(t *testing.T) {
+	s := runCreate(t, "--tmpfs", "/tmp:rw,size=64m")
+	found := false
+	for _, m := range s.Mounts {
+		if m.Destination == "/tmp" && m.Type == "tmpfs" {
+			found = true
+			break
+		}
+	}
+	assert.True(t, found, "expected a tmpfs mount at /tmp")
+}
+
+// TestCreateDevice verifies --device bind-mounts the host device node into
+// the spec's device list.
+func TestCreateDevice(t *testing.T) {
+	s := runCreate(t, "--device", "/dev/null:/dev/xnull")
+	found := false
+	for _, d := range s.Linux.Devices {
+		if d.Path == "/dev/xnull" {
+			found = true
+			break
+		}
+	}
+	assert.True(t, found, "expected a device at /dev/xnull")
+}
+
+// TestCreateUlimit verifies --ulimit is reflected as a process rlimit.
+func TestCreateUlimit(t *testing.T) {
+	s := runCreate(t, "--ulimit", "nofile=1024:2048")
+	found := false
+	for _, rl := range s.Process.Rlimits {
+		if rl.Type == "RLIMIT_NOFILE" {
+			assert.Equal(t, uint64(1024), rl.Soft)
+			assert.Equal(t, uint64(2048), rl.Hard)
+			found = true
+			break
+		}
+	}
+	assert.True(t, found, "expected a RLIMIT_NOFILE rlimit")
+}
+
+// TestCreateSecurityOptNoNewPrivileges verifies --security-opt
+// no-new-privileges disables privilege escalation in the spec.
+func TestCreateSecurityOptNoNewPrivileges(t *testing.T) {
+	s := runCreate(t, "--security-opt", "no-new-privileges")
+	assert.False(t, s.Process.NoNewPrivileges)
+}
+
+// TestCreateResourceFlagsInvalid asserts that malformed resource flag values
+// surface an error from parseCreateOpts/createConfigToOCISpec instead of
+// panicking or silently producing a zero-valued spec.
+func TestCreateResourceFlagsInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"pids-limit", []string{"--pids-limit", "not-a-number"}},
+		{"cpu-shares", []string{"--cpu-shares", "not-a-number"}},
+		{"memory", []string{"--memory", "not-a-size"}},
+		{"memory-swap", []string{"--memory", "100m", "--memory-swap", "not-a-size"}},
+		{"blkio-weight", []string{"--blkio-weight", "not-a-number"}},
+		{"ulimit", []string{"--ulimit", "nofile"}},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			if err := runCreateErr(t, tt.args...); err == nil {
+				t.Fatalf("expected an error for args %v, got nil", tt.args)
+			}
+		})
+	}
+}
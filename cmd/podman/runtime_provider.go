@@ -0,0 +1,15 @@
+package main
+
+import (
+	"github.com/projectatomic/libpod/libpod"
+	"github.com/urfave/cli"
+)
+
+// runtimeProvider resolves the libpod.Runtime used while building a
+// container's OCI spec during create. It defaults to getRuntime; tests
+// substitute a fake implementation so that exercising parseCreateOpts and
+// createConfigToOCISpec doesn't require a real libpod runtime with storage
+// and root privileges configured.
+var runtimeProvider = func(c *cli.Context) (*libpod.Runtime, error) {
+	return getRuntime(c)
+}
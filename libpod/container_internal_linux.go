@@ -4,6 +4,7 @@
 package libpod
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -11,7 +12,6 @@ import (
 	"path"
 	"path/filepath"
 	"strings"
-	"sync"
 	"syscall"
 	"time"
 
@@ -31,9 +31,14 @@ import (
 	"github.com/opencontainers/selinux/go-selinux"
 	"github.com/opencontainers/selinux/go-selinux/label"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"golang.org/x/sync/errgroup"
 	"golang.org/x/sys/unix"
 )
 
+// prepareTracer emits spans for prepare()'s concurrent stages.
+var prepareTracer = otel.Tracer("github.com/containers/podman/v4/libpod")
+
 var (
 	bindOptions = []string{"bind", "rprivate"}
 )
@@ -57,78 +62,138 @@ func (c *Container) unmountSHM(mount string) error {
 	return nil
 }
 
-// prepare mounts the container and sets up other required resources like net
-// namespaces
+// PrepareErrorKind identifies which concurrent stage of prepare() failed.
+type PrepareErrorKind int
+
+const (
+	PrepareErrorNetwork PrepareErrorKind = iota
+	PrepareErrorStorage
+	PrepareErrorOverlay
+)
+
+func (k PrepareErrorKind) String() string {
+	switch k {
+	case PrepareErrorNetwork:
+		return "network"
+	case PrepareErrorStorage:
+		return "storage"
+	case PrepareErrorOverlay:
+		return "overlay"
+	default:
+		return "unknown"
+	}
+}
+
+// PrepareError wraps a failure from one of prepare()'s concurrent stages.
+type PrepareError struct {
+	Kind PrepareErrorKind
+	ID   string
+	Err  error
+}
+
+func (e *PrepareError) Error() string {
+	return fmt.Sprintf("preparing container %s: %s stage failed: %v", e.ID, e.Kind, e.Err)
+}
+
+func (e *PrepareError) Unwrap() error {
+	return e.Err
+}
+
+// prepare mounts the container and sets up other required resources like
+// net namespaces, running its stages concurrently via errgroup; a failed
+// stage's checkpoint-checking siblings skip new work, though one already in
+// flight still runs to completion.
 func (c *Container) prepare() error {
+	ctx, span := prepareTracer.Start(context.Background(), "prepare")
+	defer span.End()
+
 	var (
-		wg                              sync.WaitGroup
-		netNS                           ns.NetNS
-		networkStatus                   map[string]types.StatusBlock
-		createNetNSErr, mountStorageErr error
-		mountPoint                      string
-		tmpStateLock                    sync.Mutex
+		netNS          ns.NetNS
+		networkStatus  map[string]types.StatusBlock
+		mountPoint     string
+		storageMounted bool
+		overlayDirs    []string
 	)
 
-	wg.Add(2)
+	g, gCtx := errgroup.WithContext(ctx)
+
+	g.Go(func() error {
+		_, span := prepareTracer.Start(gCtx, "prepare.network")
+		defer span.End()
+		// Canceled by a sibling's real error; don't let a bare ctx.Err()
+		// race ahead of that error and become the group's reported error.
+		if gCtx.Err() != nil {
+			return nil
+		}
 
-	go func() {
-		defer wg.Done()
 		// Set up network namespace if not already set up
 		noNetNS := c.state.NetNS == nil
-		if c.config.CreateNetNS && noNetNS && !c.config.PostConfigureNetNS {
-			netNS, networkStatus, createNetNSErr = c.runtime.createNetNS(c)
-			if createNetNSErr != nil {
-				return
-			}
-
-			tmpStateLock.Lock()
-			defer tmpStateLock.Unlock()
+		if !c.config.CreateNetNS || !noNetNS || c.config.PostConfigureNetNS {
+			return nil
+		}
 
-			// Assign NetNS attributes to container
-			c.state.NetNS = netNS
-			c.state.NetworkStatus = networkStatus
+		var err error
+		netNS, networkStatus, err = c.runtime.createNetNS(c)
+		if err != nil {
+			return &PrepareError{Kind: PrepareErrorNetwork, ID: c.ID(), Err: err}
 		}
-	}()
-	// Mount storage if not mounted
-	go func() {
-		defer wg.Done()
-		mountPoint, mountStorageErr = c.mountStorage()
 
-		if mountStorageErr != nil {
-			return
+		// Assign NetNS attributes to container
+		c.state.NetNS = netNS
+		c.state.NetworkStatus = networkStatus
+		return nil
+	})
+
+	g.Go(func() error {
+		_, span := prepareTracer.Start(gCtx, "prepare.storage")
+		defer span.End()
+		if gCtx.Err() != nil {
+			return nil
 		}
 
-		tmpStateLock.Lock()
-		defer tmpStateLock.Unlock()
+		var err error
+		mountPoint, err = c.mountStorage()
+		if err != nil {
+			return &PrepareError{Kind: PrepareErrorStorage, ID: c.ID(), Err: err}
+		}
 
 		// Finish up mountStorage
 		c.state.Mounted = true
 		c.state.Mountpoint = mountPoint
+		storageMounted = true
 
 		logrus.Debugf("Created root filesystem for container %s at %s", c.ID(), c.state.Mountpoint)
-	}()
+		return nil
+	})
 
-	wg.Wait()
+	g.Go(func() error {
+		_, span := prepareTracer.Start(gCtx, "prepare.overlay")
+		defer span.End()
+		if gCtx.Err() != nil {
+			return nil
+		}
 
-	var createErr error
-	if createNetNSErr != nil {
-		createErr = createNetNSErr
-	}
-	if mountStorageErr != nil {
-		if createErr != nil {
-			logrus.Errorf("Preparing container %s: %v", c.ID(), createErr)
+		// mountStorage (above) already mounts the container's SHM;
+		// this stage only pre-creates overlay upper/work dirs so the
+		// OCI runtime doesn't pay that cost serially at mount time.
+		dirs, err := c.makeOverlayUpperDirs()
+		overlayDirs = dirs
+		if err != nil {
+			return &PrepareError{Kind: PrepareErrorOverlay, ID: c.ID(), Err: err}
 		}
-		createErr = mountStorageErr
-	}
+		return nil
+	})
+
+	createErr := g.Wait()
 
 	// Only trigger storage cleanup if mountStorage was successful.
 	// Otherwise, we may mess up mount counters.
-	if createNetNSErr != nil && mountStorageErr == nil {
+	if createErr != nil && storageMounted {
 		if err := c.cleanupStorage(); err != nil {
 			// createErr is guaranteed non-nil, so print
 			// unconditionally
 			logrus.Errorf("Preparing container %s: %v", c.ID(), createErr)
-			createErr = fmt.Errorf("error unmounting storage for container %s after network create failure: %w", c.ID(), err)
+			createErr = fmt.Errorf("error unmounting storage for container %s after setup failure: %w", c.ID(), err)
 		}
 	}
 
@@ -141,6 +206,16 @@ func (c *Container) prepare() error {
 		}
 	}
 
+	// Best-effort: roll back overlay upper/work dirs this prepare() call
+	// created if something else in the group failed.
+	if createErr != nil {
+		for _, dir := range overlayDirs {
+			if err := os.RemoveAll(dir); err != nil {
+				logrus.Errorf("Cleaning up overlay dir %s for container %s: %v", dir, c.ID(), err)
+			}
+		}
+	}
+
 	if createErr != nil {
 		return createErr
 	}
@@ -153,6 +228,21 @@ func (c *Container) prepare() error {
 	return nil
 }
 
+// makeOverlayUpperDirs pre-creates overlay volumes' upper/work dirs, returning
+// the ones it created so a failed prepare() can roll them back.
+func (c *Container) makeOverlayUpperDirs() ([]string, error) {
+	var created []string
+	for _, vol := range c.config.OverlayVolumes {
+		for _, dir := range []string{filepath.Join(vol.Source, "upper"), filepath.Join(vol.Source, "work")} {
+			if err := os.MkdirAll(dir, 0o700); err != nil {
+				return created, fmt.Errorf("preparing overlay upper dir %s: %w", dir, err)
+			}
+			created = append(created, dir)
+		}
+	}
+	return created, nil
+}
+
 // cleanupNetwork unmounts and cleans up the container's network
 func (c *Container) cleanupNetwork() error {
 	if c.config.NetNsCtr != "" {
@@ -201,6 +291,15 @@ func (c *Container) reloadNetwork() error {
 
 // systemd expects to have /run, /run/lock and /tmp on tmpfs
 // It also expects to be able to write to /sys/fs/cgroup/systemd and /var/log/journal
+// hostCgroupBindMount is the pre-delegation fallback: a rw bind mount of the
+// host's whole /sys/fs/cgroup.
+var hostCgroupBindMount = spec.Mount{
+	Destination: "/sys/fs/cgroup",
+	Type:        "bind",
+	Source:      "/sys/fs/cgroup",
+	Options:     []string{"bind", "private", "rw"},
+}
+
 func (c *Container) setupSystemd(mounts []spec.Mount, g generate.Generator) error {
 	var containerUUIDSet bool
 	for _, s := range c.config.Spec.Process.Env {
@@ -255,20 +354,26 @@ func (c *Container) setupSystemd(mounts []spec.Mount, g generate.Generator) erro
 		}
 
 		var systemdMnt spec.Mount
-		if hasCgroupNs {
+		switch {
+		case hasCgroupNs:
 			systemdMnt = spec.Mount{
 				Destination: "/sys/fs/cgroup",
 				Type:        "cgroup",
 				Source:      "cgroup",
 				Options:     []string{"private", "rw"},
 			}
-		} else {
-			systemdMnt = spec.Mount{
-				Destination: "/sys/fs/cgroup",
-				Type:        "bind",
-				Source:      "/sys/fs/cgroup",
-				Options:     []string{"bind", "private", "rw"},
+		case c.wantsCgroupDelegation():
+			delegatedMnt, ok, err := c.setupDelegatedCgroup()
+			if err != nil {
+				return err
+			}
+			if ok {
+				systemdMnt = delegatedMnt
+			} else {
+				systemdMnt = hostCgroupBindMount
 			}
+		default:
+			systemdMnt = hostCgroupBindMount
 		}
 		g.AddMount(systemdMnt)
 	} else {
@@ -305,6 +410,93 @@ func (c *Container) setupSystemd(mounts []spec.Mount, g generate.Generator) erro
 	return nil
 }
 
+// delegatedCgroupControllers are handed to a container's delegated cgroup
+// subtree via cgroup.subtree_control.
+var delegatedCgroupControllers = []string{"cpu", "memory", "io", "pids"}
+
+// cgroupDelegationAnnotation opts a container into its own delegated cgroup2
+// subtree instead of a read-write bind mount of the host's /sys/fs/cgroup.
+// Set via the OCI spec's Annotations map; containers.conf's default (if any)
+// is baked into this annotation at create time, upstream of this file.
+const cgroupDelegationAnnotation = "io.podman.annotations.cgroup-delegation"
+
+// wantsCgroupDelegation reports whether this container should get its own
+// delegated cgroup2 subtree.
+func (c *Container) wantsCgroupDelegation() bool {
+	return c.config.Spec.Annotations[cgroupDelegationAnnotation] == "true"
+}
+
+// setupDelegatedCgroup creates a fresh sub-cgroup for the container under
+// the host's unified cgroup hierarchy, delegates delegatedCgroupControllers
+// to it via cgroup.subtree_control, and returns a cgroup2 mount exposing
+// only that subtree - with nsdelegate when the kernel supports it - instead
+// of bind-mounting the host's /sys/fs/cgroup read-write. ok is false, with a
+// zero Mount, when the container has no cgroup of its own to delegate (e.g.
+// NoCgroups, or rootless with no CgroupParent), or when the cgroup manager is
+// systemd - callers must fall back to the host bind mount rather than
+// delegating the host's cgroup root itself.
+func (c *Container) setupDelegatedCgroup() (mnt spec.Mount, ok bool, err error) {
+	// getOCICgroupPath returns a real filesystem path relative to
+	// /sys/fs/cgroup only for the cgroupfs manager. For the systemd manager
+	// it returns an opaque "slice:prefix:name" specifier that the OCI
+	// runtime resolves itself (see setCgroupsPath); joining that against
+	// /sys/fs/cgroup would create and delegate an unrelated bogus
+	// directory, so delegation is cgroupfs-manager only for now.
+	if c.CgroupManager() != config.CgroupfsCgroupsManager {
+		return spec.Mount{}, false, nil
+	}
+
+	cgroupPath, err := c.getOCICgroupPath()
+	if err != nil {
+		return spec.Mount{}, false, err
+	}
+	if cgroupPath == "" {
+		return spec.Mount{}, false, nil
+	}
+
+	hostCgroup := filepath.Join("/sys/fs/cgroup", cgroupPath)
+	if err := os.MkdirAll(hostCgroup, 0o755); err != nil {
+		return spec.Mount{}, false, fmt.Errorf("creating delegated cgroup %s: %w", hostCgroup, err)
+	}
+
+	subtreeControl := make([]string, 0, len(delegatedCgroupControllers))
+	for _, ctrl := range delegatedCgroupControllers {
+		subtreeControl = append(subtreeControl, "+"+ctrl)
+	}
+	controlFile := filepath.Join(hostCgroup, "cgroup.subtree_control")
+	if err := os.WriteFile(controlFile, []byte(strings.Join(subtreeControl, " ")), 0o644); err != nil {
+		return spec.Mount{}, false, fmt.Errorf("delegating controllers to %s: %w", hostCgroup, err)
+	}
+
+	options := []string{"private", "rw"}
+	if cgroupNsdelegateSupported() {
+		options = append(options, "nsdelegate")
+	}
+
+	return spec.Mount{
+		Destination: "/sys/fs/cgroup",
+		Type:        "cgroup2",
+		Source:      hostCgroup,
+		Options:     options,
+	}, true, nil
+}
+
+// cgroupNsdelegateSupported reports whether the running kernel's cgroup2
+// filesystem understands the nsdelegate mount option (advertised via
+// /sys/fs/cgroup/cgroup.features since Linux 5.19).
+func cgroupNsdelegateSupported() bool {
+	data, err := os.ReadFile("/sys/fs/cgroup/cgroup.features")
+	if err != nil {
+		return false
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.TrimSpace(line) == "nsdelegate" {
+			return true
+		}
+	}
+	return false
+}
+
 // Add an existing container's namespace to the spec
 func (c *Container) addNamespaceContainer(g *generate.Generator, ns LinuxNS, ctr string, specNS spec.LinuxNamespaceType) error {
 	nsCtr, err := c.runtime.state.Container(ctr)
@@ -397,6 +589,8 @@ func (c *Container) getOCICgroupPath() (string, error) {
 	}
 }
 
+// copyTimezoneFile copies a zoneinfo file from the host into the
+// container's run directory; used by HostFileTimezoneSource.
 func (c *Container) copyTimezoneFile(zonePath string) (string, error) {
 	localtimeCopy := filepath.Join(c.state.RunDir, "localtime")
 	file, err := os.Stat(zonePath)
@@ -617,6 +811,15 @@ func (c *Container) addSystemdMounts(g *generate.Generator) error {
 	return nil
 }
 
+// addTimezone adds the container's resolved /etc/localtime mount and TZ=
+// environment variable to the spec. Like addSystemdMounts/addSharedNamespaces
+// in this file, it has no caller in this source tree: whatever assembles the
+// container's generate.Generator isn't part of this checkout and needs to
+// call it alongside those.
+func (c *Container) addTimezone(g *generate.Generator) error {
+	return c.setupTimezone(g)
+}
+
 func (c *Container) addSharedNamespaces(g *generate.Generator) error {
 	if c.config.IPCNsCtr != "" {
 		if err := c.addNamespaceContainer(g, IPCNS, c.config.IPCNsCtr, spec.IPCNamespace); err != nil {
@@ -0,0 +1,120 @@
+//go:build linux
+// +build linux
+
+package libpod
+
+import (
+	"embed"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+
+	spec "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/opencontainers/runtime-tools/generate"
+)
+
+//go:embed zoneinfo
+var embeddedZoneinfo embed.FS
+
+// TimezoneSource supplies a container's /etc/localtime data and the IANA
+// name to inject as TZ=.
+type TimezoneSource interface {
+	// Resolve returns the host-side path to bind-mount at /etc/localtime
+	// (empty for the "local"/"Etc/UTC" shortcuts) and the IANA zone name.
+	Resolve(c *Container) (localtimePath string, ianaName string, err error)
+}
+
+// resolveShortcut handles zone names that need no zoneinfo file.
+func resolveShortcut(zone string) (ianaName string, ok bool) {
+	switch zone {
+	case "", "local":
+		return "local", true
+	case "Etc/UTC", "UTC":
+		return "Etc/UTC", true
+	default:
+		return "", false
+	}
+}
+
+// HostFileTimezoneSource copies a zoneinfo file out of the host's
+// /usr/share/zoneinfo, as podman has always done.
+type HostFileTimezoneSource struct {
+	// Zone is the IANA timezone name, e.g. "America/New_York".
+	Zone string
+}
+
+// Resolve implements TimezoneSource.
+func (s HostFileTimezoneSource) Resolve(c *Container) (string, string, error) {
+	if ianaName, ok := resolveShortcut(s.Zone); ok {
+		return "", ianaName, nil
+	}
+
+	zonePath := filepath.Join("/usr/share/zoneinfo", s.Zone)
+	localtimeCopy, err := c.copyTimezoneFile(zonePath)
+	if err != nil {
+		return "", "", fmt.Errorf("finding timezone %q on host: %w", s.Zone, err)
+	}
+	return localtimeCopy, s.Zone, nil
+}
+
+// EmbeddedTimezoneSource reads zoneinfo data vendored into libpod/zoneinfo,
+// for hosts without /usr/share/zoneinfo.
+type EmbeddedTimezoneSource struct {
+	// Zone is the IANA timezone name, e.g. "America/New_York".
+	Zone string
+}
+
+// Resolve implements TimezoneSource.
+func (s EmbeddedTimezoneSource) Resolve(c *Container) (string, string, error) {
+	if ianaName, ok := resolveShortcut(s.Zone); ok {
+		return "", ianaName, nil
+	}
+
+	data, err := embeddedZoneinfo.ReadFile(path.Join("zoneinfo", s.Zone))
+	if err != nil {
+		return "", "", fmt.Errorf("timezone %q is not in the embedded tzdata subset vendored into this build: %w", s.Zone, err)
+	}
+
+	localtimeCopy := filepath.Join(c.state.RunDir, "localtime")
+	if err := os.WriteFile(localtimeCopy, data, 0o644); err != nil {
+		return "", "", err
+	}
+	if err := c.relabel(localtimeCopy, c.config.MountLabel, false); err != nil {
+		return "", "", err
+	}
+	if err := os.Chown(localtimeCopy, c.RootUID(), c.RootGID()); err != nil {
+		return "", "", err
+	}
+	return localtimeCopy, s.Zone, nil
+}
+
+// timezoneSource picks the embedded tzdata subset when the host has no
+// zoneinfo database, the host's own files otherwise.
+func (c *Container) timezoneSource() TimezoneSource {
+	if _, err := os.Stat("/usr/share/zoneinfo"); err != nil {
+		return EmbeddedTimezoneSource{Zone: c.config.Timezone}
+	}
+	return HostFileTimezoneSource{Zone: c.config.Timezone}
+}
+
+// setupTimezone resolves the container's timezone and adds /etc/localtime
+// and TZ= to the spec.
+func (c *Container) setupTimezone(g *generate.Generator) error {
+	localtimePath, ianaName, err := c.timezoneSource().Resolve(c)
+	if err != nil {
+		return fmt.Errorf("setting up timezone for container %s: %w", c.ID(), err)
+	}
+
+	if localtimePath != "" {
+		g.AddMount(spec.Mount{
+			Destination: "/etc/localtime",
+			Type:        "bind",
+			Source:      localtimePath,
+			Options:     bindOptions,
+		})
+	}
+
+	g.AddProcessEnv("TZ", ianaName)
+	return nil
+}